@@ -0,0 +1,72 @@
+package batcher
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+const (
+	// blobFieldElements is the number of field elements packed into a single blob.
+	blobFieldElements = 4096
+	// usableBytesPerFieldElement is 31, not 32: the top byte of every field element
+	// must stay zero so its value stays below the BLS12-381 scalar field modulus.
+	usableBytesPerFieldElement = 31
+	// maxBlobDataSize is the usable payload capacity of a single blob: 4096 * 31 = 126,976 bytes (~124 KiB).
+	maxBlobDataSize = blobFieldElements * usableBytesPerFieldElement
+	// maxBlobsPerTx is the EIP-4844 per-tx blob cap.
+	maxBlobsPerTx = 6
+)
+
+// ErrBlobDataTooLarge is returned by encodeBlobs when data does not fit in maxBlobsPerTx blobs.
+var ErrBlobDataTooLarge = errors.New("data exceeds max blob tx capacity")
+
+// encodeBlobs packs data into canonically-encoded EIP-4844 blobs: each 32-byte field
+// element has its top byte zeroed to stay below the BLS12-381 scalar field modulus,
+// leaving 31 usable bytes per element and 4096 elements (126,976 bytes, ~124 KiB) per blob.
+func encodeBlobs(data []byte) ([]kzg4844.Blob, error) {
+	if len(data) > maxBlobsPerTx*maxBlobDataSize {
+		return nil, ErrBlobDataTooLarge
+	}
+
+	var blobs []kzg4844.Blob
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxBlobDataSize {
+			n = maxBlobDataSize
+		}
+		chunk := data[:n]
+		data = data[n:]
+
+		var blob kzg4844.Blob
+		for i := 0; i < blobFieldElements && len(chunk) > 0; i++ {
+			m := len(chunk)
+			if m > usableBytesPerFieldElement {
+				m = usableBytesPerFieldElement
+			}
+			// blob[i*32] is left zero: the high byte of the field element.
+			copy(blob[i*32+1:i*32+1+m], chunk[:m])
+			chunk = chunk[m:]
+		}
+		blobs = append(blobs, blob)
+	}
+	return blobs, nil
+}
+
+// blobTxSidecarFields computes the KZG commitments and versioned hashes for a set of
+// blobs, ready to populate a txmgr.TxCandidate submitting them as a type-3 tx.
+func blobTxSidecarFields(blobs []kzg4844.Blob) (commitments []kzg4844.Commitment, hashes []common.Hash, err error) {
+	commitments = make([]kzg4844.Commitment, len(blobs))
+	hashes = make([]common.Hash, len(blobs))
+	for i, blob := range blobs {
+		c, err := kzg4844.BlobToCommitment(&blob)
+		if err != nil {
+			return nil, nil, err
+		}
+		commitments[i] = c
+		hashes[i] = kzg4844.CalcBlobHashV1(sha256.New(), &c)
+	}
+	return commitments, hashes, nil
+}
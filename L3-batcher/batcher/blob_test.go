@@ -0,0 +1,60 @@
+package batcher
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestEncodeBlobsRoundTrip checks that encodeBlobs packs data into the expected
+// number of blobs and that every usable byte survives, with the top (zeroed) byte
+// of each field element skipped correctly on the way in.
+func TestEncodeBlobsRoundTrip(t *testing.T) {
+	cases := []struct {
+		name      string
+		size      int
+		wantBlobs int
+	}{
+		{"empty", 0, 0},
+		{"one byte", 1, 1},
+		{"exactly one blob", maxBlobDataSize, 1},
+		{"one blob plus one byte", maxBlobDataSize + 1, 2},
+		{"max capacity", maxBlobsPerTx * maxBlobDataSize, maxBlobsPerTx},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data := make([]byte, c.size)
+			for i := range data {
+				data[i] = byte(i)
+			}
+			blobs, err := encodeBlobs(data)
+			if err != nil {
+				t.Fatalf("encodeBlobs: %v", err)
+			}
+			if len(blobs) != c.wantBlobs {
+				t.Fatalf("got %d blobs, want %d", len(blobs), c.wantBlobs)
+			}
+
+			var out []byte
+			for _, blob := range blobs {
+				for i := 0; i < blobFieldElements; i++ {
+					if blob[i*32] != 0 {
+						t.Fatalf("field element %d has a non-zero top byte", i)
+					}
+					out = append(out, blob[i*32+1:i*32+32]...)
+				}
+			}
+			out = out[:len(data)]
+			if !bytes.Equal(out, data) {
+				t.Fatal("decoded blob data does not match input")
+			}
+		})
+	}
+}
+
+// TestEncodeBlobsTooLarge checks that data exceeding the per-tx blob capacity is rejected.
+func TestEncodeBlobsTooLarge(t *testing.T) {
+	data := make([]byte, maxBlobsPerTx*maxBlobDataSize+1)
+	if _, err := encodeBlobs(data); err != ErrBlobDataTooLarge {
+		t.Fatalf("got err %v, want ErrBlobDataTooLarge", err)
+	}
+}
@@ -0,0 +1,123 @@
+package batcher
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/ethereum-optimism/optimism/op-node/rollup/derive"
+)
+
+// channel accumulates a run of L2 blocks under a single derive.ChannelID and,
+// once closed, cuts their RLP-encoded, zlib-compressed bytes into one or more
+// frames no larger than cfg.TargetSizeForType(daType). Every frame it produces
+// is tagged with the same chID and a frame number that only ever increases,
+// which is what lets the channel manager hand out a unique txID per frame. The
+// tag lives on frameData only: a frame's data is a bare slice of the compressed
+// channel bytes, with no derivation frame header.
+type channel struct {
+	id          derive.ChannelID
+	openL1Block uint64
+
+	// blocks holds the blocks folded into this channel so far, kept around (rather
+	// than compressed incrementally) so the channel can be checkpointed and resumed
+	// without needing to serialize an in-progress zlib writer's internal state.
+	blocks  []*types.Block
+	rawSize int
+
+	closed          bool
+	frames          []frameData
+	nextFrameNumber uint16
+}
+
+// newChannel opens a new channel. openL1Block is the tip observed when the first
+// block was folded in, used by ReadyToClose to enforce cfg.ChannelTimeout.
+func newChannel(openL1Block uint64) (*channel, error) {
+	var id derive.ChannelID
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, fmt.Errorf("generating channel id: %w", err)
+	}
+	return &channel{id: id, openL1Block: openL1Block}, nil
+}
+
+// AddBlock folds block into the channel.
+func (c *channel) AddBlock(block *types.Block) error {
+	raw, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return fmt.Errorf("encoding block for channel: %w", err)
+	}
+	c.blocks = append(c.blocks, block)
+	c.rawSize += len(raw)
+	return nil
+}
+
+// ReadyToClose reports whether the channel has accumulated cfg.TargetSizeForType(daType)
+// worth of (uncompressed) block data, or has been open since before curL1Block minus
+// cfg.ChannelTimeout - whichever comes first. Using the uncompressed size as the target
+// trigger is conservative (the compressed output will usually be smaller), which keeps
+// this a cheap running check rather than one that needs to recompress on every block.
+func (c *channel) ReadyToClose(cfg ChannelConfig, daType DataAvailabilityType, curL1Block uint64) bool {
+	if target := cfg.TargetSizeForType(daType); target > 0 && uint64(c.rawSize) >= target {
+		return true
+	}
+	if cfg.ChannelTimeout != 0 && curL1Block >= c.openL1Block+cfg.ChannelTimeout {
+		return true
+	}
+	return false
+}
+
+// Close compresses the channel's accumulated blocks and cuts the result into
+// frames of at most cfg.TargetSizeForType(daType) bytes. It is idempotent.
+func (c *channel) Close(cfg ChannelConfig, daType DataAvailabilityType) error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if len(c.blocks) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	for _, block := range c.blocks {
+		raw, err := rlp.EncodeToBytes(block)
+		if err != nil {
+			return fmt.Errorf("encoding block for channel: %w", err)
+		}
+		var lenPrefix [8]byte
+		binary.BigEndian.PutUint64(lenPrefix[:], uint64(len(raw)))
+		if _, err := zw.Write(lenPrefix[:]); err != nil {
+			return fmt.Errorf("compressing channel block: %w", err)
+		}
+		if _, err := zw.Write(raw); err != nil {
+			return fmt.Errorf("compressing channel block: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("closing channel compressor: %w", err)
+	}
+
+	data := buf.Bytes()
+	maxFrame := int(cfg.TargetSizeForType(daType))
+	if maxFrame <= 0 {
+		maxFrame = len(data)
+	}
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxFrame {
+			n = maxFrame
+		}
+		c.frames = append(c.frames, frameData{
+			data: data[:n],
+			id:   txID{chID: c.id, frameNumber: c.nextFrameNumber},
+		})
+		c.nextFrameNumber++
+		data = data[n:]
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+package batcher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-batcher/metrics"
+	"github.com/ethereum-optimism/optimism/op-node/rollup"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+)
+
+// ErrReorg is returned when AddL2Block is called with a block that does not extend the last-added block.
+var ErrReorg = errors.New("block does not extend existing chain")
+
+// ErrPendingAfterClose is returned by Close when one or more channels still have data pending submission.
+var ErrPendingAfterClose = errors.New("pending channels remain after close")
+
+// channelManager accepts L2 blocks and turns them into channels of frames ready to submit to L1.
+// It is also the sole owner of everything a ChannelStore needs to checkpoint: most mutating
+// methods persist a fresh checkpoint before returning when store is configured, except
+// AddL2Block and SetLastStoredBlock, which a caller may call many times in a loop and which
+// only mark state dirty - call Flush once the loop is done to persist it (see Flush's doc comment).
+type channelManager struct {
+	mu    sync.Mutex
+	log   log.Logger
+	metr  metrics.Metricer
+	cfg   ChannelConfig
+	rcfg  *rollup.Config
+	store ChannelStore
+
+	// lastStoredBlock is the last L3 block loaded into this manager, and lastL1Tip
+	// is the last L1 tip observed by the driver. Both live here, rather than on
+	// BatchSubmitter, so a single checkpoint captures everything needed to resume.
+	lastStoredBlock eth.BlockID
+	lastL1Tip       eth.L1BlockRef
+
+	// blocks holds L2 blocks that have been added but not yet packed into a channel.
+	blocks []*types.Block
+
+	// curChannel is the channel currently accumulating blocks pulled off of blocks,
+	// or nil if none is open. It is cut into pendingFrames once ReadyToClose.
+	curChannel *channel
+
+	// pendingFrames holds frames that have been cut from blocks and are ready to send.
+	pendingFrames []frameData
+
+	// pendingTxIDs holds frames that have been handed to a caller via TxData and are
+	// presumed in flight - sent but not yet confirmed or failed.
+	pendingTxIDs map[txID]frameData
+
+	closed bool
+
+	// dirty is true if state has changed since the last write to store. AddL2Block
+	// and SetLastStoredBlock set it without writing, so a caller driving many of
+	// them in a loop (loadBlocksIntoState, once per block) can bound the number of
+	// checkpoint writes - each of which re-encodes every currently queued block -
+	// to one per loop via Flush, rather than one per block.
+	dirty bool
+}
+
+// NewChannelManager initializes a new channelManager. If store is non-nil and has a
+// previously saved checkpoint, state is restored from it instead of starting empty.
+func NewChannelManager(log log.Logger, metr metrics.Metricer, cfg ChannelConfig, rcfg *rollup.Config, store ChannelStore) (*channelManager, error) {
+	s := &channelManager{
+		log:          log,
+		metr:         metr,
+		cfg:          cfg,
+		rcfg:         rcfg,
+		store:        store,
+		pendingTxIDs: make(map[txID]frameData),
+	}
+
+	if store == nil {
+		return s, nil
+	}
+	cp, ok, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading channel manager checkpoint: %w", err)
+	}
+	if !ok {
+		return s, nil
+	}
+
+	s.lastStoredBlock = cp.LastStoredBlock
+	s.lastL1Tip = cp.LastL1Tip
+	s.pendingFrames = cp.PendingFrames
+	for _, f := range cp.PendingTxIDs {
+		s.pendingTxIDs[f.id] = f
+	}
+	for _, raw := range cp.Blocks {
+		block, err := decodeBlockFromCheckpoint(raw)
+		if err != nil {
+			return nil, fmt.Errorf("decoding checkpointed block: %w", err)
+		}
+		s.blocks = append(s.blocks, block)
+	}
+	if len(cp.CurChannelBlocks) > 0 {
+		c, err := newChannel(cp.CurChannelOpenL1Block)
+		if err != nil {
+			return nil, fmt.Errorf("reopening in-progress channel from checkpoint: %w", err)
+		}
+		for _, raw := range cp.CurChannelBlocks {
+			block, err := decodeBlockFromCheckpoint(raw)
+			if err != nil {
+				return nil, fmt.Errorf("decoding checkpointed in-progress channel block: %w", err)
+			}
+			if err := c.AddBlock(block); err != nil {
+				return nil, fmt.Errorf("restoring in-progress channel block: %w", err)
+			}
+		}
+		s.curChannel = c
+	}
+	log.Info("restored channel manager state from checkpoint",
+		"last_stored_block", s.lastStoredBlock, "pending_blocks", len(s.blocks),
+		"pending_frames", len(s.pendingFrames), "pending_tx_ids", len(s.pendingTxIDs))
+	return s, nil
+}
+
+// checkpoint marks state dirty and immediately persists it, if a store is
+// configured. Must be called with s.mu held. Used by every mutating method except
+// AddL2Block/SetLastStoredBlock, which only markDirty and leave the actual write
+// to a later Flush - see Flush's doc comment for why.
+func (s *channelManager) checkpoint() {
+	s.markDirty()
+	s.flushLocked()
+}
+
+// markDirty records that state has changed since the last flush, without writing
+// anything to store. Must be called with s.mu held.
+func (s *channelManager) markDirty() {
+	s.dirty = true
+}
+
+// Flush persists any state changes since the last checkpoint/Flush, if a store is
+// configured and something is actually dirty. AddL2Block and SetLastStoredBlock
+// only markDirty rather than writing immediately, so a caller that calls them
+// once per block in a loop (loadBlocksIntoState, catching up after a restart) can
+// call Flush once when the loop is done instead of paying a checkpoint write -
+// which re-encodes every currently queued block - once per block, which would
+// turn an n-block catch-up into O(n^2) work.
+func (s *channelManager) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushLocked()
+}
+
+// flushLocked does the actual write to store if one is configured and state is
+// dirty. Must be called with s.mu held. Errors are logged rather than returned:
+// persistence is a best-effort durability feature, not one that should make an
+// in-memory operation fail just because the disk write did.
+func (s *channelManager) flushLocked() {
+	if s.store == nil || !s.dirty {
+		return
+	}
+	cp := Checkpoint{
+		LastStoredBlock: s.lastStoredBlock,
+		LastL1Tip:       s.lastL1Tip,
+		PendingFrames:   s.pendingFrames,
+	}
+	for _, block := range s.blocks {
+		raw, err := encodeBlockForCheckpoint(block)
+		if err != nil {
+			s.log.Error("failed to encode block for channel manager checkpoint", "err", err)
+			return
+		}
+		cp.Blocks = append(cp.Blocks, raw)
+	}
+	if s.curChannel != nil {
+		cp.CurChannelOpenL1Block = s.curChannel.openL1Block
+		for _, block := range s.curChannel.blocks {
+			raw, err := encodeBlockForCheckpoint(block)
+			if err != nil {
+				s.log.Error("failed to encode in-progress channel block for checkpoint", "err", err)
+				return
+			}
+			cp.CurChannelBlocks = append(cp.CurChannelBlocks, raw)
+		}
+	}
+	for _, f := range s.pendingTxIDs {
+		cp.PendingTxIDs = append(cp.PendingTxIDs, f)
+	}
+	if err := s.store.Save(cp); err != nil {
+		s.log.Error("failed to save channel manager checkpoint", "err", err)
+		return
+	}
+	s.dirty = false
+}
+
+// LastStoredBlock returns the last L3 block loaded into this manager.
+func (s *channelManager) LastStoredBlock() eth.BlockID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastStoredBlock
+}
+
+// SetLastStoredBlock records the last L3 block loaded into this manager. It only
+// marks state dirty rather than writing immediately - see Flush's doc comment.
+func (s *channelManager) SetLastStoredBlock(id eth.BlockID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastStoredBlock = id
+	s.markDirty()
+}
+
+// LastL1Tip returns the last L1 tip recorded via RecordL1Tip.
+func (s *channelManager) LastL1Tip() eth.L1BlockRef {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastL1Tip
+}
+
+// RecordL1Tip records the last L1 tip observed by the driver.
+func (s *channelManager) RecordL1Tip(tip eth.L1BlockRef) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastL1Tip = tip
+	s.checkpoint()
+}
+
+// ReconcilePending decides the fate of every tx ID restored from a checkpoint as
+// in flight: haveConfirmed is consulted for each with that frame's data, and is
+// expected to check whatever L1 history the caller has available - channelManager
+// itself has no L1 visibility, so it cannot make this call on its own. A confirmed
+// frame is dropped; anything haveConfirmed can't positively confirm is conservatively
+// re-queued as a pending frame so it gets resubmitted (txmgr's own nonce handling is
+// expected to no-op a resubmission that turns out to have already landed).
+func (s *channelManager) ReconcilePending(haveConfirmed func(id txID, data []byte) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, f := range s.pendingTxIDs {
+		delete(s.pendingTxIDs, id)
+		if haveConfirmed(id, f.data) {
+			continue
+		}
+		s.pendingFrames = append(s.pendingFrames, f)
+	}
+	s.checkpoint()
+}
+
+// PendingTxIDCount returns the number of tx IDs presumed in flight (handed out via
+// TxData but not yet confirmed or failed). NewBatchSubmitter uses this to decide
+// whether a startup L1 reconciliation scan is worth doing at all.
+func (s *channelManager) PendingTxIDCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pendingTxIDs)
+}
+
+// AddL2Block appends block to the local queue of blocks not yet packed into a channel.
+// It returns ErrReorg if block does not extend the previously added block. It only
+// marks state dirty rather than writing immediately - see Flush's doc comment.
+func (s *channelManager) AddL2Block(block *types.Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.blocks) > 0 {
+		last := s.blocks[len(s.blocks)-1]
+		if block.ParentHash() != last.Hash() {
+			return ErrReorg
+		}
+	}
+	s.blocks = append(s.blocks, block)
+	s.markDirty()
+	return nil
+}
+
+// PendingBlocks returns the number of L2 blocks queued but not yet packed into a channel.
+func (s *channelManager) PendingBlocks() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.blocks)
+}
+
+// IsFull reports whether the manager is holding at least cfg.MaxPendingBlocks blocks in
+// memory, which callers use to throttle loading further blocks until L1 catches up.
+// A MaxPendingBlocks of 0 means unbounded, so IsFull is always false in that case.
+func (s *channelManager) IsFull() bool {
+	if s.cfg.MaxPendingBlocks == 0 {
+		return false
+	}
+	return s.PendingBlocks() >= int(s.cfg.MaxPendingBlocks)
+}
+
+// TxData returns the next tx payload ready for submission, packing queued blocks into
+// channels and cutting those into frames sized for the given DA type as needed. It
+// returns io.EOF if there is nothing left to send.
+func (s *channelManager) TxData(l1Head eth.BlockID, daType DataAvailabilityType) (txData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pendingFrames) == 0 {
+		if err := s.packBlocksLocked(l1Head, daType); err != nil {
+			return txData{}, err
+		}
+	}
+	if len(s.pendingFrames) == 0 {
+		return txData{}, io.EOF
+	}
+
+	f := s.pendingFrames[0]
+	s.pendingFrames = s.pendingFrames[1:]
+	s.pendingTxIDs[f.id] = f
+	td := singleFrameTxData(f.id, f.data)
+	td.isBlob = daType == BlobsType
+	s.checkpoint()
+	return td, nil
+}
+
+// packBlocksLocked folds queued blocks into s.curChannel until it is ready to close
+// (per ChannelConfig.MaxFrameSize/TargetSizeForType and ChannelTimeout, judged
+// against l1Head) or s.blocks runs out, then cuts a ready channel into pendingFrames.
+// Must be called with s.mu held.
+func (s *channelManager) packBlocksLocked(l1Head eth.BlockID, daType DataAvailabilityType) error {
+	for len(s.blocks) > 0 {
+		if s.curChannel == nil {
+			c, err := newChannel(l1Head.Number)
+			if err != nil {
+				return fmt.Errorf("opening channel: %w", err)
+			}
+			s.curChannel = c
+		}
+		if s.curChannel.ReadyToClose(s.cfg, daType, l1Head.Number) {
+			break
+		}
+		block := s.blocks[0]
+		s.blocks = s.blocks[1:]
+		if err := s.curChannel.AddBlock(block); err != nil {
+			return fmt.Errorf("packing block into channel: %w", err)
+		}
+	}
+	if s.curChannel == nil {
+		return nil
+	}
+	if !s.curChannel.ReadyToClose(s.cfg, daType, l1Head.Number) && !s.closed {
+		return nil
+	}
+	if err := s.curChannel.Close(s.cfg, daType); err != nil {
+		return fmt.Errorf("closing channel: %w", err)
+	}
+	s.pendingFrames = append(s.pendingFrames, s.curChannel.frames...)
+	s.curChannel = nil
+	return nil
+}
+
+// TxFailed marks the frame(s) for id as failed so they are requeued for resubmission.
+func (s *channelManager) TxFailed(id txID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log.Warn("marking channel manager tx as failed", "id", id)
+	if f, ok := s.pendingTxIDs[id]; ok {
+		delete(s.pendingTxIDs, id)
+		s.pendingFrames = append(s.pendingFrames, f)
+	}
+	s.checkpoint()
+}
+
+// TxConfirmed marks the frame(s) for id as confirmed in the given L1 block.
+func (s *channelManager) TxConfirmed(id txID, block eth.BlockID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log.Info("marking channel manager tx as confirmed", "id", id, "block", block)
+	delete(s.pendingTxIDs, id)
+	s.checkpoint()
+}
+
+// Clear drops all in-memory state, discarding any unconfirmed channels and frames.
+func (s *channelManager) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocks = nil
+	s.curChannel = nil
+	s.pendingFrames = nil
+	s.pendingTxIDs = make(map[txID]frameData)
+	s.closed = false
+	s.checkpoint()
+}
+
+// Close terminates any open channel so its buffered data can be flushed to L1.
+// It returns ErrPendingAfterClose if there is still data that needs to be submitted.
+func (s *channelManager) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.curChannel != nil {
+		// daType is unknown at Close time (no in-flight publish call to ask), so the
+		// still-open channel is cut to calldata-sized frames: the conservative default
+		// also used elsewhere when DA type can't be resolved.
+		if err := s.curChannel.Close(s.cfg, CalldataType); err != nil {
+			s.log.Error("failed to close in-progress channel on shutdown", "err", err)
+		} else {
+			s.pendingFrames = append(s.pendingFrames, s.curChannel.frames...)
+			s.curChannel = nil
+		}
+	}
+	s.checkpoint()
+	if len(s.blocks) > 0 || len(s.pendingFrames) > 0 || s.curChannel != nil {
+		return ErrPendingAfterClose
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package batcher
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+)
+
+func testBlock(number uint64, parent common.Hash) *types.Block {
+	return types.NewBlockWithHeader(&types.Header{
+		Number:     new(big.Int).SetUint64(number),
+		ParentHash: parent,
+	})
+}
+
+// TestChannelManagerTxDataUniqueIDs guards against a bug where every frame's txID
+// was computed from len(s.pendingFrames) at a point where that length was always
+// zero: every frame/tx got the identical zero-value txID, and pendingTxIDs (a
+// map[txID]frameData) would silently clobber an older in-flight frame's entry as
+// soon as more than one tx was in flight.
+func TestChannelManagerTxDataUniqueIDs(t *testing.T) {
+	// A small MaxFrameSize forces each block's channel to close (and get cut into
+	// frames) as soon as that one block is folded in, so each TxData call below
+	// exercises a distinct channel.
+	cfg := ChannelConfig{MaxFrameSize: 200}
+	s, err := NewChannelManager(log.Root(), nil, cfg, nil, nil)
+	if err != nil {
+		t.Fatalf("NewChannelManager: %v", err)
+	}
+
+	seen := make(map[txID]bool)
+	var parent common.Hash
+	for i := uint64(1); i <= 4; i++ {
+		b := testBlock(i, parent)
+		parent = b.Hash()
+		if err := s.AddL2Block(b); err != nil {
+			t.Fatalf("AddL2Block(%d): %v", i, err)
+		}
+
+		for {
+			td, err := s.TxData(eth.BlockID{Number: i}, CalldataType)
+			if err != nil {
+				break // io.EOF: nothing left to send until the next block is added
+			}
+			id := td.ID()
+			if seen[id] {
+				t.Fatalf("TxData returned a previously-seen txID %v after adding block %d", id, i)
+			}
+			seen[id] = true
+			if len(td.Bytes()) == 0 {
+				t.Fatalf("TxData returned an empty frame for txID %v", id)
+			}
+		}
+	}
+	if len(seen) == 0 {
+		t.Fatal("TxData never returned any frames")
+	}
+}
@@ -0,0 +1,124 @@
+package batcher
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+)
+
+// checkpointBucket is the single bbolt bucket the checkpoint lives in.
+var checkpointBucket = []byte("channel-manager-checkpoint")
+
+// checkpointKey is the single key the checkpoint is stored under: there is
+// only ever one checkpoint, so there is no need for a keyspace.
+var checkpointKey = []byte("checkpoint")
+
+// Checkpoint is everything channelManager needs to resume exactly where it
+// left off: blocks not yet packed into frames, frames not yet sent, frames
+// sent but not yet confirmed, and the tips loadBlocksIntoState/publishTxToL1
+// use to decide what comes next.
+type Checkpoint struct {
+	LastStoredBlock eth.BlockID
+	LastL1Tip       eth.L1BlockRef
+
+	// Blocks holds RLP-encoded blocks, since types.Block doesn't round-trip
+	// through encoding/json on its own.
+	Blocks [][]byte
+
+	// CurChannelBlocks holds the RLP-encoded blocks already folded into the
+	// in-progress channel (not yet cut into frames), if one was open.
+	// CurChannelOpenL1Block is the L1 tip observed when that channel was opened,
+	// used to resume enforcement of ChannelConfig.ChannelTimeout. A fresh
+	// derive.ChannelID is assigned on restore: no frame has been cut from this
+	// data yet, so nothing outside this checkpoint has seen - or needs - the old one.
+	CurChannelBlocks      [][]byte
+	CurChannelOpenL1Block uint64
+
+	PendingFrames []frameData
+	// PendingTxIDs holds the full frames behind each tx ID presumed in flight
+	// (handed out by TxData, but with no TxFailed/TxConfirmed yet), not just the
+	// bare IDs, so a restored-but-unconfirmed frame can be resubmitted verbatim.
+	PendingTxIDs []frameData
+}
+
+// ChannelStore persists channelManager's state to disk so a crash or restart
+// doesn't force the batcher to fall back to re-deriving and recompressing
+// everything since the L2/L3 safe head. It is optional: a nil ChannelStore is
+// a valid, and the default, in-memory-only configuration.
+type ChannelStore interface {
+	// Save persists cp, replacing whatever checkpoint was saved before.
+	Save(cp Checkpoint) error
+	// Load returns the last saved checkpoint. ok is false if none has been saved yet.
+	Load() (cp Checkpoint, ok bool, err error)
+	Close() error
+}
+
+// boltChannelStore is the default ChannelStore: a single bbolt file holding
+// one JSON-encoded Checkpoint under one key. bbolt was chosen over a bigger
+// embedded database because the checkpoint is small, single-writer, and
+// doesn't need range queries - just load-on-start and overwrite-on-mutation.
+type boltChannelStore struct {
+	db *bolt.DB
+}
+
+// NewBoltChannelStore opens (creating if necessary) a bbolt file at path to use as a ChannelStore.
+func NewBoltChannelStore(path string) (ChannelStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("opening channel store at %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(checkpointBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing channel store bucket: %w", err)
+	}
+	return &boltChannelStore{db: db}, nil
+}
+
+func (s *boltChannelStore) Save(cp Checkpoint) error {
+	raw, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("encoding checkpoint: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(checkpointBucket).Put(checkpointKey, raw)
+	})
+}
+
+func (s *boltChannelStore) Load() (cp Checkpoint, ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(checkpointBucket).Get(checkpointKey)
+		if raw == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(raw, &cp)
+	})
+	return cp, ok, err
+}
+
+func (s *boltChannelStore) Close() error {
+	return s.db.Close()
+}
+
+// encodeBlockForCheckpoint and decodeBlockFromCheckpoint convert between
+// *types.Block and the RLP bytes Checkpoint stores it as.
+func encodeBlockForCheckpoint(block *types.Block) ([]byte, error) {
+	return rlp.EncodeToBytes(block)
+}
+
+func decodeBlockFromCheckpoint(raw []byte) (*types.Block, error) {
+	var block types.Block
+	if err := rlp.DecodeBytes(raw, &block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
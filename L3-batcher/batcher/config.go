@@ -0,0 +1,60 @@
+package batcher
+
+import "time"
+
+const (
+	// DefaultMaxPendingBlocks is a value for ChannelConfig.MaxPendingBlocks: with it
+	// set, the driver stops loading new L2 blocks once the channel manager holds
+	// this many in memory. Nothing in this package applies it on its own; a
+	// ChannelConfig whose MaxPendingBlocks is left at 0 is unbounded.
+	DefaultMaxPendingBlocks = 10
+)
+
+// DataAvailabilityType selects the transaction type used to post channel frames
+// to the batch inbox.
+type DataAvailabilityType string
+
+const (
+	// CalldataType posts channel frames as plain calldata in a type-0/2 tx.
+	CalldataType DataAvailabilityType = "calldata"
+	// BlobsType posts channel frames as EIP-4844 blobs in a type-3 tx.
+	BlobsType DataAvailabilityType = "blobs"
+	// AutoType picks calldata or blobs per-tx, whichever is currently cheaper.
+	AutoType DataAvailabilityType = "auto"
+)
+
+// BatcherConfig contains the configuration for the batch-submitter driver.
+type BatcherConfig struct {
+	NetworkTimeout time.Duration
+	PollInterval   time.Duration
+
+	// MaxPendingTransactions is the maximum number of transactions that can be in-flight at once.
+	MaxPendingTransactions uint64
+
+	// DataAvailabilityType selects whether channel frames are submitted as calldata,
+	// blobs, or chosen automatically per-tx based on current L1 fee market conditions.
+	DataAvailabilityType DataAvailabilityType
+}
+
+// ChannelConfig contains the configuration for the channel manager's channel-building process.
+type ChannelConfig struct {
+	ChannelTimeout uint64
+	MaxFrameSize   uint64
+
+	// MaxPendingBlocks bounds how many L2 blocks may sit in the channel manager's
+	// in-memory queue before loadBlocksIntoState backs off. This prevents unbounded
+	// memory growth when L1 is slow to confirm transactions or the txpool is blocked.
+	// A value of 0 disables the limit.
+	MaxPendingBlocks uint64
+}
+
+// TargetSizeForType returns the target output size of a single tx payload for the
+// given DA type: the configured calldata frame-size target, or the full per-tx
+// blob capacity (maxBlobsPerTx blobs) when posting as blobs - a single blob's
+// worth would leave most of a blob tx's capacity unused.
+func (c ChannelConfig) TargetSizeForType(daType DataAvailabilityType) uint64 {
+	if daType == BlobsType {
+		return maxBlobsPerTx * maxBlobDataSize
+	}
+	return c.MaxFrameSize
+}
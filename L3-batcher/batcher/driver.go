@@ -8,11 +8,17 @@ import (
 	"math/big"
 	_ "net/http/pprof"
 	"sync"
-	"time"
+	"sync/atomic"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+
+	core_op "github.com/ethereum-optimism/op-geth/core"
 
 	"github.com/ethereum-optimism/optimism/op-batcher/metrics"
 	"github.com/ethereum-optimism/optimism/op-node/rollup"
@@ -24,36 +30,90 @@ import (
 
 var ErrBatcherNotRunning = errors.New("batcher is not running")
 
+// txpoolState tracks the batcher's understanding of whether its txs are
+// currently able to land in the connected node's local mempool.
+type txpoolState int32
+
+const (
+	// TxpoolGood is the default state: the txpool is accepting the batcher's txs normally.
+	TxpoolGood txpoolState = iota
+	// TxpoolBlocked means a receipt came back with txpool.ErrAlreadyReserved: some other
+	// pending tx from the batcher's sending address is reserving its nonce slot, and new
+	// frames of the offending type must not be submitted until it clears.
+	TxpoolBlocked
+	// TxpoolCancelPending means a self-cancellation tx has been sent to clear the blocking
+	// tx, and the batcher is waiting for its receipt before resuming normal submission.
+	TxpoolCancelPending
+)
+
+// l2Health tracks whether the L2 sequencer - the default L3 batch-inbox target -
+// is believed to be live. It is read from the pipeline and publishWorker
+// goroutines, so it is held behind an atomic rather than guarded by a mutex.
+type l2Health int32
+
+const (
+	// l2Healthy is the default state: the L2 sequencer is including L3 batches
+	// promptly, so the batcher posts to the L2 inbox.
+	l2Healthy l2Health = iota
+	// l2Stalled means the L2 sequencer has fallen behind by more than
+	// RollupConfig.SeqWindowSize L3 blocks without making them safe, so the
+	// batcher fails over to posting directly to the L1 inbox.
+	l2Stalled
+)
+
+// l2HealthHysteresis is how many consecutive polls must agree before getL2Status
+// flips l2Health, so a single transient RPC error or momentarily-slow L2 block
+// doesn't cause the batch-inbox target to flap back and forth.
+const l2HealthHysteresis = 2
+
 type L1Client interface {
 	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	// BlockByNumber is used by NewBatchSubmitter's startup reconciliation scan,
+	// which needs full transactions - not just headers - to check whether a
+	// frame restored from a checkpoint as "in flight" already landed on L1
+	// before the batcher's last checkpoint.
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
 }
 
 type L2Client interface {
 	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
 }
 
+// L3Client is a cheap, non-retrying client against the L3 execution layer: the
+// chain the batcher is submitting on behalf of. It is used for quick liveness
+// checks (getL2Status) where going through EndpointProvider's retry/failover
+// logic would be unnecessary overhead.
 type L3Client interface {
 	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
-	// 수정 필요함
 }
 
 type RollupClient interface {
 	SyncStatus(ctx context.Context) (*eth.SyncStatus, error)
 }
 
-// L3Client에 대한 인터페이스를 구현하고, 아래 DriverSetup에 L2 Client를 포함
-
 // DriverSetup is the collection of input/output interfaces and configuration that the driver operates on.
 type DriverSetup struct {
-	Log              log.Logger
-	Metr             metrics.Metricer
-	RollupConfig     *rollup.Config
-	Config           BatcherConfig
-	Txmgr            txmgr.TxManager
-	L1Client         L1Client
-	L2Client		 L2Client // L2Client를 추가
+	Log          log.Logger
+	Metr         metrics.Metricer
+	RollupConfig *rollup.Config
+	Config       BatcherConfig
+	Txmgr        txmgr.TxManager
+	L1Client     L1Client
+	// L2Client is the default batch-inbox target: an OP Stack L2 that accepts
+	// L3 batches the same way L1 accepts L2 batches. It is used directly
+	// (rather than through an EndpointProvider) for the same reason L1Client is.
+	L2Client L2Client
+	L3Client L3Client
+	// EndpointProvider gives retrying, failover-aware access to the L3 chain
+	// being batched: loadBlockIntoState reads L3 blocks through it, and
+	// calculateL2BlockRangeToStore reads the L3 rollup node's sync status through it.
 	EndpointProvider dial.L2EndpointProvider
 	ChannelConfig    ChannelConfig
+
+	// ChannelStore optionally checkpoints the channel manager's state to disk so a
+	// crash or restart can resume without re-deriving and recompressing everything
+	// since the L2/L3 safe head. A nil ChannelStore means no persistence.
+	ChannelStore ChannelStore
 }
 
 // BatchSubmitter encapsulates a service responsible for submitting L2 tx
@@ -71,24 +131,100 @@ type BatchSubmitter struct {
 	mutex   sync.Mutex
 	running bool
 
-	// lastStoredBlock is the last block loaded into `state`. If it is empty it should be set to the l2 safe head.
-	lastStoredBlock eth.BlockID
-	lastL1Tip       eth.L1BlockRef
+	// txpoolState and txpoolBlockedBlob record whether the local node's mempool is
+	// currently refusing the batcher's txs, and if so whether the blocking tx was a
+	// blob tx, so publishTxToL1 knows which frame type to hold back.
+	txpoolState       atomic.Int32
+	txpoolBlockedBlob bool
 
-	// 위 역할을 하는 l2를 위한 포함 요소 추가
-	// op.BlockID와 op.L2BlockRef는 구현된 함수가 아닌 가상의 함수
-	lastStoredBlockInL2 op.BlockID
-	lastL2Tip           op.L2BlockRef
+	// l2Health and l2StallStreak implement the dual-DA failover: l2Health is the
+	// last-decided batch-inbox target, consulted by calculateL2BlockRangeToStore,
+	// publishTxToL1 and sendTransaction. l2StallStreak counts consecutive polls
+	// that disagree with the current l2Health, so a single missed or ambiguous
+	// poll can't flap the target chain - see getL2Status.
+	l2Health      atomic.Int32
+	l2StallStreak int
 
 	state *channelManager
 }
 
-// NewBatchSubmitter initializes the BatchSubmitter driver from a preconfigured DriverSetup
-func NewBatchSubmitter(setup DriverSetup) *BatchSubmitter {
-	return &BatchSubmitter{
+// NewBatchSubmitter initializes the BatchSubmitter driver from a preconfigured DriverSetup.
+// If setup.ChannelStore has a previously saved checkpoint, the channel manager resumes from
+// it instead of starting empty.
+func NewBatchSubmitter(setup DriverSetup) (*BatchSubmitter, error) {
+	state, err := NewChannelManager(setup.Log, setup.Metr, setup.ChannelConfig, setup.RollupConfig, setup.ChannelStore)
+	if err != nil {
+		return nil, fmt.Errorf("initializing channel manager: %w", err)
+	}
+	l := &BatchSubmitter{
 		DriverSetup: setup,
-		state:       NewChannelManager(setup.Log, setup.Metr, setup.ChannelConfig, setup.RollupConfig),
+		state:       state,
+	}
+	// Any tx ID restored from a checkpoint was only ever known to be sent, not
+	// confirmed. scanL1ForConfirmedFrames below does a best-effort, bounded scan of recent L1
+	// blocks to find which of those, if any, actually landed; anything it can't
+	// positively confirm is conservatively resubmitted - see scanL1ForConfirmedFrames's
+	// doc comment for the scan's limits (calldata-only, bounded window).
+	if state.PendingTxIDCount() > 0 {
+		confirmed, err := l.scanL1ForConfirmedFrames(context.Background())
+		if err != nil {
+			l.Log.Warn("failed to scan L1 for already-confirmed pending frames, conservatively resubmitting all of them", "err", err)
+			confirmed = nil
+		}
+		l.state.ReconcilePending(func(id txID, data []byte) bool {
+			return confirmed[string(data)]
+		})
+	}
+	return l, nil
+}
+
+// l1ReconcileScanLimit bounds how many L1 blocks scanL1ForConfirmedFrames looks
+// back over, so a long-stale checkpoint can't turn batcher startup into an
+// unbounded scan of L1 history.
+const l1ReconcileScanLimit = 256
+
+// scanL1ForConfirmedFrames looks for txs already sent from the batcher's own
+// address to one of the batch inbox addresses, in L1 blocks between the last
+// checkpointed L1 tip and the current head (capped at l1ReconcileScanLimit
+// blocks), and returns the set of calldata payloads found. This only recognizes
+// calldata-mode frames - a blob-carried frame's payload isn't part of the block
+// itself - so blob frames, and anything outside the scanned window, are left
+// unconfirmed and conservatively resubmitted by ReconcilePending's caller.
+func (l *BatchSubmitter) scanL1ForConfirmedFrames(ctx context.Context) (map[string]bool, error) {
+	tctx, cancel := context.WithTimeout(ctx, l.Config.NetworkTimeout)
+	head, err := l.L1Client.HeaderByNumber(tctx, nil)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("getting L1 head: %w", err)
+	}
+
+	startBlock := l.state.LastL1Tip().Number
+	if head.Number.Uint64() > startBlock+l1ReconcileScanLimit {
+		startBlock = head.Number.Uint64() - l1ReconcileScanLimit
 	}
+
+	sender := l.Txmgr.From()
+	found := make(map[string]bool)
+	for n := startBlock; n <= head.Number.Uint64(); n++ {
+		tctx, cancel := context.WithTimeout(ctx, l.Config.NetworkTimeout)
+		block, err := l.L1Client.BlockByNumber(tctx, new(big.Int).SetUint64(n))
+		cancel()
+		if err != nil {
+			return found, fmt.Errorf("getting L1 block %d: %w", n, err)
+		}
+		for _, tx := range block.Transactions() {
+			to := tx.To()
+			if to == nil || (*to != l.RollupConfig.BatchInboxAddressL2 && *to != l.RollupConfig.BatchInboxAddressL1) {
+				continue
+			}
+			txSender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+			if err != nil || txSender != sender {
+				continue
+			}
+			found[string(tx.Data())] = true
+		}
+	}
+	return found, nil
 }
 
 func (l *BatchSubmitter) StartBatchSubmitting() error {
@@ -104,8 +240,9 @@ func (l *BatchSubmitter) StartBatchSubmitting() error {
 
 	l.shutdownCtx, l.cancelShutdownCtx = context.WithCancel(context.Background())
 	l.killCtx, l.cancelKillCtx = context.WithCancel(context.Background())
-	l.state.Clear()
-	l.lastStoredBlock = eth.BlockID{}
+	// l.state is deliberately left as NewBatchSubmitter set it up (empty, or
+	// restored from a checkpoint): clearing it here would defeat the point of
+	// persisting it in the first place if the process had just restarted.
 
 	l.wg.Add(1)
 	go l.loop()
@@ -160,6 +297,16 @@ func (l *BatchSubmitter) StopBatchSubmitting(ctx context.Context) error {
 // If there is a reorg, it will reset the last stored block but not clear the internal state so
 // the state can be flushed to L1.
 func (l *BatchSubmitter) loadBlocksIntoState(ctx context.Context) error {
+	// AddL2Block (inside loadBlockIntoState) and SetLastStoredBlock, called below and
+	// from calculateL2BlockRangeToStore, only mark state dirty rather than persisting
+	// it immediately - a checkpoint write re-encodes every queued block, so doing one
+	// per block here would turn an n-block catch-up into O(n^2) work. Flush once,
+	// covering every path out of this function, persists whatever progress was made
+	// even on an early return.
+	defer func() {
+		l.state.Flush()
+	}()
+
 	start, end, err := l.calculateL2BlockRangeToStore(ctx)
 	if err != nil {
 		l.Log.Warn("Error calculating L2 block range", "err", err)
@@ -174,13 +321,13 @@ func (l *BatchSubmitter) loadBlocksIntoState(ctx context.Context) error {
 		block, err := l.loadBlockIntoState(ctx, i)
 		if errors.Is(err, ErrReorg) {
 			l.Log.Warn("Found L2 reorg", "block_number", i)
-			l.lastStoredBlock = eth.BlockID{}
+			l.state.SetLastStoredBlock(eth.BlockID{})
 			return err
 		} else if err != nil {
 			l.Log.Warn("failed to load block into state", "err", err)
 			return err
 		}
-		l.lastStoredBlock = eth.ToBlockID(block)
+		l.state.SetLastStoredBlock(eth.ToBlockID(block))
 		latestBlock = block
 	}
 
@@ -216,7 +363,7 @@ func (l *BatchSubmitter) loadBlockIntoState(ctx context.Context, blockNumber uin
 }
 
 // calculateL2BlockRangeToStore determines the range (start,end] that should be loaded into the local state.
-// It also takes care of initializing some local state (i.e. will modify l.lastStoredBlock in certain conditions)
+// It also takes care of initializing some local state (i.e. will modify l.state's lastStoredBlock in certain conditions)
 func (l *BatchSubmitter) calculateL2BlockRangeToStore(ctx context.Context) (eth.BlockID, eth.BlockID, error) {
 	ctx, cancel := context.WithTimeout(ctx, l.Config.NetworkTimeout)
 	defer cancel()
@@ -224,13 +371,11 @@ func (l *BatchSubmitter) calculateL2BlockRangeToStore(ctx context.Context) (eth.
 	if err != nil {
 		return eth.BlockID{}, eth.BlockID{}, fmt.Errorf("getting rollup client: %w", err)
 	}
+	// syncStatus reports L3 safety the same way op-node normally reports L2
+	// safety, but split across the two chains an L3 batch can be made safe in:
+	// UnsafeL3 is the latest L3 block, SafeInL2 is the last one included in an L2
+	// batch, and SafeInL1 is the last one included (via L2's own batches) in L1.
 	syncStatus, err := rollupClient.SyncStatus(ctx)
-	/* todo
-	syncStatus를 받아올 때, 기존 safe, unsafe 말고
-	L3에서 사용하는 unsafe, safeInL2, safeInL1을 반환해주도록
-	SyncStatus 함수를 수정해줘야 함
-	*/
-	// Ensure that we have the sync status
 	if err != nil {
 		return eth.BlockID{}, eth.BlockID{}, fmt.Errorf("failed to get sync status: %w", err)
 	}
@@ -240,25 +385,30 @@ func (l *BatchSubmitter) calculateL2BlockRangeToStore(ctx context.Context) (eth.
 
 	// Check last stored to see if it needs to be set on startup OR set if is lagged behind.
 	// It lagging implies that the op-node processed some batches that were submitted prior to the current instance of the batcher being alive.
-	if l.lastStoredBlock == (eth.BlockID{}) {
-		l.Log.Info("Starting batch-submitter work at safe-head", "safe", syncStatus.SafeL2)
-		l.lastStoredBlock = syncStatus.SafeL2.ID()
-	} else if l.lastStoredBlock.Number < syncStatus.SafeL2.Number {
-		l.Log.Warn("last submitted block lagged behind L2 safe head: batch submission will continue from the safe head now", "last", l.lastStoredBlock, "safe", syncStatus.SafeL2)
-		l.lastStoredBlock = syncStatus.SafeL2.ID()
+	lastStoredBlock := l.state.LastStoredBlock()
+	if lastStoredBlock == (eth.BlockID{}) {
+		l.Log.Info("Starting batch-submitter work at safe-head", "safe", syncStatus.SafeInL2)
+		lastStoredBlock = syncStatus.SafeInL2.ID()
+		l.state.SetLastStoredBlock(lastStoredBlock)
+	} else if lastStoredBlock.Number < syncStatus.SafeInL2.Number {
+		l.Log.Warn("last submitted block lagged behind L3 safe-in-L2 head: batch submission will continue from there now", "last", lastStoredBlock, "safe", syncStatus.SafeInL2)
+		lastStoredBlock = syncStatus.SafeInL2.ID()
+		l.state.SetLastStoredBlock(lastStoredBlock)
 	}
 
 	// Check if we should even attempt to load any blocks. TODO: May not need this check
-	if syncStatus.SafeL2.Number >= syncStatus.UnsafeL2.Number {
-		return eth.BlockID{}, eth.BlockID{}, errors.New("L2 safe head ahead of L2 unsafe head")
+	if syncStatus.SafeInL2.Number >= syncStatus.UnsafeL3.Number {
+		return eth.BlockID{}, eth.BlockID{}, errors.New("L3 safe head ahead of L3 unsafe head")
 	}
 
-	if(L2Status == 1) {
-		return syncStatus.SafeInL1.ID(), syncStatus.UnsafeL2.ID(), nil
-		// SafeInL1이 된 마지막 블록 다음부터를 싱크할 블록으로 설정함
+	if l.getL2Status(ctx, syncStatus) == l2Stalled {
+		// L2 isn't making progress, so resume from the last block that made it
+		// safe on L1 instead - re-deriving from SafeInL2 would re-send data the
+		// stalled L2 inbox never processed.
+		return syncStatus.SafeInL1.ID(), syncStatus.UnsafeL3.ID(), nil
 	}
 
-	return l.lastStoredBlock, syncStatus.UnsafeL2.ID(), nil
+	return lastStoredBlock, syncStatus.UnsafeL3.ID(), nil
 }
 
 // The following things occur:
@@ -272,147 +422,273 @@ func (l *BatchSubmitter) calculateL2BlockRangeToStore(ctx context.Context) (eth.
 // Submitted batch, but it is not valid
 // Missed L2 block somehow.
 
-/* todo
-1. L2가 멈춘 것을 감지 -> loop 시작 부분에 정상 상태 / L2 장애 상태로 case를 나누어 변수에 할당
-2. L2가 멈춘 것이 확인 -> SWS 동안의 데이터를 L1으로 올려주는 코드
-3. L2가 복구 -> L2로 제출 위치 다시 변경
-*/
+// loop is the top-level driver. It owns no blocking work itself: loading L2 blocks
+// is delegated to the pipeline, and sending txs to L1 to the txManager (via the
+// persistent publishWorker goroutine). loop's single select multiplexes the
+// pipeline's reorg/loaded signals, receiptsCh (tx outcomes) and shutdownCtx.Done(),
+// reacting to each by pulsing publishTick to wake publishWorker rather than ever
+// blocking on a send to L1 itself.
+//
+// The pipeline can be stopped and restarted - on a reorg, or on a fatal tx failure -
+// without tearing down the txManager, so in-flight nonce tracking survives restarts.
 func (l *BatchSubmitter) loop() {
 	defer l.wg.Done()
 
-	ticker := time.NewTicker(l.Config.PollInterval)
-	defer ticker.Stop()
-	/*
-	todo: 1. L2가 멈춘 것을 감지를 구현하는 수도코드를 loop 아래의 getL2Status() 함수에 구현
-
-	L2가 멈춘 것을 감지하는 함수, getL2Status() 라고 가정, 정상이면 0, 멈췄으면 1 리턴한다고 가정
-	L2Status := getStatus()
-	*/
 	receiptsCh := make(chan txmgr.TxReceipt[txData])
-	queue := txmgr.NewQueue[txData](l.killCtx, l.Txmgr, l.Config.MaxPendingTransactions)
+	txMgr := newTxManager(l.Log, l.killCtx, l.Txmgr, l.Config.MaxPendingTransactions)
+	pl := newPipeline(l)
+
+	// publishTick is pulsed whenever the loop believes there may be new tx data
+	// ready to publish. It is buffered to depth 1: pulsing is a hint, not a queue,
+	// so a burst of pulses while the worker is busy collapses into a single retry.
+	publishTick := make(chan struct{}, 1)
+	requestPublish := func() {
+		select {
+		case publishTick <- struct{}{}:
+		default:
+		}
+	}
+
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		l.publishWorker(txMgr, publishTick, receiptsCh)
+	}()
+
+	// stopRequested is set while a pipeline restart is being handled and cleared by
+	// restartPipeline once the new pipeline has started. A burst of fatal receipts
+	// is folded into a single restart by the drain in the receiptsCh case below.
+	stopRequested := false
+	restartPipeline := func(reason string) {
+		pl.Stop()
+		// Any tx still in flight was carrying data from the channel manager state
+		// we are about to clear; its eventual receipt (if any) should no longer
+		// affect nonce bookkeeping.
+		txMgr.DiscardPending()
+		if err := l.state.Close(); err != nil {
+			if errors.Is(err, ErrPendingAfterClose) {
+				l.Log.Warn("Closed channel manager to restart pipeline with pending channel(s) remaining - submitting", "reason", reason)
+			} else {
+				l.Log.Error("Error closing the channel manager to restart pipeline", "err", err, "reason", reason)
+			}
+		}
+		requestPublish()
+		l.state.Clear()
+		stopRequested = false
+		pl.Start(l.shutdownCtx)
+	}
+
+	pl.Start(l.shutdownCtx)
 
 	for {
 		select {
-		case <-ticker.C:
-			if err := l.loadBlocksIntoState(l.shutdownCtx); errors.Is(err, ErrReorg) {
-				// 위 함수에 대해 l2가 멈춘 상황에 대한 코드를 수정함
-				err := l.state.Close()
-				if err != nil {
-					if errors.Is(err, ErrPendingAfterClose) {
-						l.Log.Warn("Closed channel manager to handle L2 reorg with pending channel(s) remaining - submitting")
-					} else {
-						l.Log.Error("Error closing the channel manager to handle a L2 reorg", "err", err)
-					}
-				}
-				l.publishStateToL1(queue, receiptsCh, true)
-				l.state.Clear()
+		case <-pl.reorgCh:
+			if stopRequested {
 				continue
 			}
-			l.publishStateToL1(queue, receiptsCh, false)
+			stopRequested = true
+			l.Log.Warn("Found L2 reorg, restarting pipeline; txManager stays up")
+			restartPipeline("reorg")
+		case <-pl.loadedCh:
+			requestPublish()
 		case r := <-receiptsCh:
-			l.handleReceipt(r)
+			if fatal := l.handleReceipt(txMgr, r); !fatal {
+				requestPublish()
+				continue
+			}
+			if stopRequested {
+				continue
+			}
+			stopRequested = true
+			l.Log.Error("Confirmed tx failure; draining other pending receipts before stopping the pipeline")
+			// Handle every receipt already waiting behind this one first, without
+			// letting any of them trigger a restart of its own, so a burst of failures
+			// from the same bad batch produces one pipeline restart instead of a storm
+			// of them. Txs still in flight after that are discarded by restartPipeline.
+		drainPending:
+			for {
+				select {
+				case r2 := <-receiptsCh:
+					l.handleReceipt(txMgr, r2)
+				default:
+					break drainPending
+				}
+			}
+			restartPipeline("fatal tx failure")
 		case <-l.shutdownCtx.Done():
 			// This removes any never-submitted pending channels, so these do not have to be drained with transactions.
 			// Any remaining unfinished channel is terminated, so its data gets submitted.
-			err := l.state.Close()
-			if err != nil {
+			pl.Stop()
+			if err := l.state.Close(); err != nil {
 				if errors.Is(err, ErrPendingAfterClose) {
 					l.Log.Warn("Closed channel manager on shutdown with pending channel(s) remaining - submitting")
 				} else {
 					l.Log.Error("Error closing the channel manager on shutdown", "err", err)
 				}
 			}
-			l.publishStateToL1(queue, receiptsCh, true)
-			l.Log.Info("Finished publishing all remaining channel data")
-			return
+			requestPublish()
+			close(publishTick)
+			// txMgr.Wait() blocks until every tx the queue is tracking has completed,
+			// which includes txs publishWorker already handed off before workerDone
+			// closes. Those sends may still be blocked pushing their result onto the
+			// unbuffered receiptsCh, so Wait() must run concurrently with - not after -
+			// draining it, or the two goroutines deadlock on each other.
+			waitDone := make(chan struct{})
+			go func() {
+				defer close(waitDone)
+				txMgr.Wait()
+			}()
+		drain:
+			for {
+				select {
+				case r := <-receiptsCh:
+					l.handleReceipt(txMgr, r)
+				case <-workerDone:
+					break drain
+				}
+			}
+			for {
+				select {
+				case r := <-receiptsCh:
+					l.handleReceipt(txMgr, r)
+				case <-waitDone:
+					l.Log.Info("Finished publishing all remaining channel data")
+					return
+				}
+			}
 		}
 	}
 }
 
-// todo 위에 언급된 L2 상태를 확인하는 함수를 구현
-func getL2Status() {
-	// block derivation 코드를 이용하자
-	// op-node / rollup / derive / l1_traversal.go 코드를 이용
-	AdvancedL1Block(ctx context.Context);
-	// 이걸로 다음 l1 블록의 header 정보를 읽어옴 + L1 reorg 여부를 파악
-	// 다음 L1 블록의 receipt를 가져온 후 UpdateSystemConfigWithL1Receipts 함수를 통해 L1 system configuration을 업데이트하고, 이어서 블록의 Header를 L1Traversal 구조체에 업데이트
-
-	// 그 후 L1 Retrieval 코드를 이용하자 (사실 호출 순서는 L1 retrieval -> L1 trieval)
-	// op-node / rollup / derive / l1_retrieval.go
-	NextData(ctx context.Context);
-	// 블록 header 정보가 존재한다면, dataSrc의 OpenData 메소드를 호출하여 context, Next L1 block ID, batcher contract address를 받아와 블록 header 정보를 읽고 그 안에서 batcher transaction 데이터를 추출
-}
-
-
-// publishStateToL1 loops through the block data loaded into `state` and
-// submits the associated data to the L1 in the form of channel frames.
-func (l *BatchSubmitter) publishStateToL1(queue *txmgr.Queue[txData], receiptsCh chan txmgr.TxReceipt[txData], drain bool) {
-	txDone := make(chan struct{})
-	// send/wait and receipt reading must be on a separate goroutines to avoid deadlocks
-	go func() {
-		defer func() {
-			if drain {
-				// if draining, we wait for all transactions to complete
-				queue.Wait()
-			}
-			close(txDone)
-		}()
+// publishWorker is the persistent goroutine that owns the blocking path to L1,
+// sending through txMgr rather than a raw txmgr.Queue so nonce bookkeeping survives
+// pipeline restarts. It drains the channel manager fully on every pulse, then waits
+// for the next one, and exits once trigger is closed (on shutdown).
+func (l *BatchSubmitter) publishWorker(txMgr *txManager, trigger <-chan struct{}, receiptsCh chan txmgr.TxReceipt[txData]) {
+	for range trigger {
 		for {
-			err := l.publishTxToL1(l.killCtx, queue, receiptsCh)
+			err := l.publishTxToL1(l.killCtx, txMgr, receiptsCh)
 			if err != nil {
-				if drain && err != io.EOF {
-					l.Log.Error("error sending tx while draining state", "err", err)
+				if err != io.EOF {
+					l.Log.Error("error sending tx", "err", err)
 				}
-				return
+				break
 			}
 		}
-	}()
+	}
+}
 
-	for {
-		select {
-		case r := <-receiptsCh:
-			l.handleReceipt(r)
-		case <-txDone:
-			return
+// getL2Status decides whether the L2 sequencer is keeping up with L3 batch
+// inclusion, and updates/returns l.l2Health accordingly. syncStatus.SafeInL2
+// and SafeInL1 already reflect the rollup node's own derivation-pipeline view
+// of inclusion, so this reuses them rather than re-walking L2 blocks for
+// batcher-inbox data itself - that derivation logic belongs in op-node, not
+// the batcher. The one independent signal read here is the L3 unsafe head
+// itself, fetched directly from L3Client (cheaper and lower-latency than a
+// round trip through the rollup node), with syncStatus.UnsafeL3 used as a
+// fallback if that fetch fails.
+//
+// The sequencer is considered stalled once the L3 unsafe head has pulled more
+// than RollupConfig.SeqWindowSize blocks ahead of SafeInL2 without the L2
+// sequencer making further progress safe. A flip in either direction must be
+// observed l2HealthHysteresis times in a row before l2Health actually changes,
+// so a single missed poll can't flap the target chain.
+func (l *BatchSubmitter) getL2Status(ctx context.Context, syncStatus *eth.SyncStatus) l2Health {
+	unsafeL3Number := syncStatus.UnsafeL3.Number
+	if l.L3Client != nil {
+		tctx, cancel := context.WithTimeout(ctx, l.Config.NetworkTimeout)
+		head, err := l.L3Client.BlockByNumber(tctx, nil)
+		cancel()
+		if err != nil {
+			l.Log.Warn("failed to query L3 head directly for health check, falling back to sync status", "err", err)
+		} else {
+			unsafeL3Number = head.NumberU64()
 		}
 	}
+
+	current := l2Health(l.l2Health.Load())
+	want := l2Healthy
+	if unsafeL3Number > syncStatus.SafeInL2.Number+l.RollupConfig.SeqWindowSize {
+		want = l2Stalled
+	}
+
+	if want == current {
+		l.l2StallStreak = 0
+		return current
+	}
+	l.l2StallStreak++
+	if l.l2StallStreak < l2HealthHysteresis {
+		return current
+	}
+	l.l2StallStreak = 0
+	if want == l2Stalled {
+		l.Log.Warn("L2 sequencer appears stalled, failing over to L1 batch inbox", "unsafe_l3", unsafeL3Number, "safe_in_l2", syncStatus.SafeInL2)
+	} else {
+		l.Log.Info("L2 sequencer has recovered, resuming L2 batch inbox submission")
+	}
+	l.l2Health.Store(int32(want))
+	return want
+}
+
+// txpoolBlocksDAType reports whether a blocked txpool slot should hold back
+// frames of daType: true only once the txpool has actually rejected a tx as
+// TxpoolBlocked, and only for the DA type that tx was using - the other DA
+// type is unaffected and should keep being submitted normally.
+func txpoolBlocksDAType(state txpoolState, blockedBlob bool, daType DataAvailabilityType) bool {
+	return state == TxpoolBlocked && (daType == BlobsType) == blockedBlob
 }
 
 // publishTxToL1 submits a single state tx to the L1
-func (l *BatchSubmitter) publishTxToL1(ctx context.Context, queue *txmgr.Queue[txData], receiptsCh chan txmgr.TxReceipt[txData]) error {
+func (l *BatchSubmitter) publishTxToL1(ctx context.Context, txMgr *txManager, receiptsCh chan txmgr.TxReceipt[txData]) error {
+	if txpoolState(l.txpoolState.Load()) == TxpoolCancelPending {
+		// Already waiting on the cancellation's receipt, which occupies our next
+		// nonce; nothing of either DA type can go out until it clears.
+		return io.EOF
+	}
+
 	// send all available transactions
-	// L2Status의 값에 따라 해당 체인을 설정
 
-	/* 기존 코드
+	// recordL1Tip always reflects the real L1, regardless of which chain is
+	// the current batch-inbox target, since L1 is what everything is
+	// ultimately anchored to and what the metrics dashboards track.
 	l1tip, err := l.l1Tip(ctx)
 	if err != nil {
 		l.Log.Error("Failed to query L1 tip", "err", err)
 		return err
 	}
 	l.recordL1Tip(l1tip)
-	*/
 
-	// 수정한 코드, 변수 이름은 그대로 둠
-	if(L2Status == 0) {
-		l1tip, err := l.l2Tip(ctx) // l2Tip 함수는 l1Tip 함수 아래에 추가로 구현함
+	// channelTip is the reference tip channelManager.TxData uses for its own
+	// timeout bookkeeping, which has to be the tip of whichever chain frames
+	// are actually being submitted to.
+	channelTip := l1tip.ID()
+	if l2Health(l.l2Health.Load()) == l2Healthy {
+		l2tip, err := l.l2Tip(ctx)
 		if err != nil {
 			l.Log.Error("Failed to query L2 tip", "err", err)
 			return err
 		}
-		l.recordL1Tip(l1tip)
+		channelTip = l2tip.ID()
 	}
-	else {
-		// 기존 코드와 같이 L1과 상호작용
-		l1tip, err := l.l1Tip(ctx)
-		if err != nil {
-			l.Log.Error("Failed to query L1 tip", "err", err)
-			return err
-		}
-		l.recordL1Tip(l1tip)
+
+	daType, err := l.resolveDataAvailabilityType(ctx)
+	if err != nil {
+		l.Log.Warn("failed to resolve data availability type, falling back to calldata", "err", err)
+		daType = CalldataType
+	}
+
+	if txpoolBlocksDAType(txpoolState(l.txpoolState.Load()), l.txpoolBlockedBlob, daType) {
+		// The local mempool has an incompatible pending tx of this same DA type
+		// reserving our next nonce. Stop feeding it new frames of that type and
+		// instead clear it with a self-cancellation; frames of the other DA type
+		// keep flowing normally until resolveDataAvailabilityType picks this one again.
+		l.txpoolState.Store(int32(TxpoolCancelPending))
+		l.sendCancelTx(txMgr, receiptsCh)
+		return nil
 	}
 
 	// Collect next transaction data
-	txdata, err := l.state.TxData(l1tip.ID())
+	txdata, err := l.state.TxData(channelTip, daType)
 	if err == io.EOF {
 		l.Log.Trace("no transaction data available")
 		return err
@@ -421,73 +697,147 @@ func (l *BatchSubmitter) publishTxToL1(ctx context.Context, queue *txmgr.Queue[t
 		return err
 	}
 
-	l.sendTransaction(txdata, queue, receiptsCh)
+	l.sendTransaction(txdata, txMgr, receiptsCh)
 	return nil
 }
 
+// resolveDataAvailabilityType decides whether the next tx should be submitted as
+// calldata or as blobs. In explicit calldata/blobs mode it simply returns the
+// configured type; in auto mode it compares the current L1 blob base fee against
+// the calldata-equivalent cost (base fee times the non-zero-byte calldata gas cost)
+// and picks whichever is cheaper.
+func (l *BatchSubmitter) resolveDataAvailabilityType(ctx context.Context) (DataAvailabilityType, error) {
+	if l.Config.DataAvailabilityType != AutoType {
+		return l.Config.DataAvailabilityType, nil
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, l.Config.NetworkTimeout)
+	defer cancel()
+	head, err := l.L1Client.HeaderByNumber(tctx, nil)
+	if err != nil {
+		return CalldataType, fmt.Errorf("getting L1 head for DA type selection: %w", err)
+	}
+	if head.ExcessBlobGas == nil {
+		// Pre-Cancun head: blobs are not available yet.
+		return CalldataType, nil
+	}
+
+	// Every byte of blob data costs one unit of blob gas, so the blob base fee is
+	// already a per-byte price.
+	blobBaseFeePerByte := eip4844.CalcBlobFee(*head.ExcessBlobGas)
+	calldataCostPerByte := new(big.Int).Mul(head.BaseFee, big.NewInt(params.TxDataNonZeroGasEIP2028))
+
+	if blobBaseFeePerByte.Cmp(calldataCostPerByte) < 0 {
+		return BlobsType, nil
+	}
+	return CalldataType, nil
+}
+
 // sendTransaction creates & submits a transaction to the batch inbox address with the given `data`.
 // It currently uses the underlying `txmgr` to handle transaction sending & price management.
 // This is a blocking method. It should not be called concurrently.
-func (l *BatchSubmitter) sendTransaction(txdata txData, queue *txmgr.Queue[txData], receiptsCh chan txmgr.TxReceipt[txData]) {
+func (l *BatchSubmitter) sendTransaction(txdata txData, txMgr *txManager, receiptsCh chan txmgr.TxReceipt[txData]) {
 	// Do the gas estimation offline. A value of 0 will cause the [txmgr] to estimate the gas limit.
 	data := txdata.Bytes()
-	if(L2Status == 0) {
-		intrinsicGas, err := core_op.IntrinsicGas(data, nil, false, true, true, false)
-		// 기존 코드는 import한 core를 이용 -> 아직 구현하지 않은 core_op로 op 가스비 가져온다고 가정함
+
+	var to *common.Address
+	var intrinsicGas uint64
+	var err error
+	switch l2Health(l.l2Health.Load()) {
+	case l2Healthy:
+		// Default path: post to the L2 inbox. It's an ordinary tx on an OP
+		// Stack L2, so gas is estimated with op-geth's intrinsic-gas variant
+		// rather than upstream go-ethereum's.
+		to = &l.RollupConfig.BatchInboxAddressL2
+		intrinsicGas, err = core_op.IntrinsicGas(data, nil, false, true, true, false)
+	case l2Stalled:
+		// Failover: post straight to L1 with the standard intrinsic-gas calculation.
+		to = &l.RollupConfig.BatchInboxAddressL1
+		intrinsicGas, err = core.IntrinsicGas(data, nil, false, true, true, false)
+	}
+	if err != nil {
+		l.Log.Error("Failed to calculate intrinsic gas", "err", err)
+		return
+	}
+
+	candidate := txmgr.TxCandidate{
+		To:       to,
+		TxData:   data,
+		GasLimit: intrinsicGas,
+	}
+
+	if txdata.isBlob {
+		blobs, err := encodeBlobs(data)
 		if err != nil {
-			l.Log.Error("Failed to calculate intrinsic gas", "err", err)
+			l.Log.Error("Failed to encode blobs", "err", err)
 			return
 		}
-		candidate := txmgr.TxCandidate{
-			To:       &l.RollupConfig.BatchInboxAddress,
-			// BatchInboxAddress에는 L2 batchInboxAddress가 저장되어 있을 것임
-			TxData:   data,
-			GasLimit: intrinsicGas,
-		}
-	}
-	else {
-		intrinsicGas, err := core.IntrinsicGas(data, nil, false, true, true, false)
+		_, hashes, err := blobTxSidecarFields(blobs)
 		if err != nil {
-			l.Log.Error("Failed to calculate intrinsic gas", "err", err)
+			l.Log.Error("Failed to compute blob KZG commitments", "err", err)
 			return
 		}
-		candidate := txmgr.TxCandidate{
-			To:       &l.RollupConfig.BatchInboxAddressL1,
-			// BatchInboxAddressL1라는 변수를 RollupConfig에 추가하여 설정해야 함
-			// 역추적하다가 어디서 정의되는지 못찾겠어서 나중에..
-			TxData:   data,
-			GasLimit: intrinsicGas,
-		}
+		// Blob txs carry their payload in the sidecar, not calldata; [txmgr] is
+		// responsible for estimating BlobGasFeeCap when it is left as nil.
+		candidate.TxData = nil
+		candidate.GasLimit = params.TxGas
+		candidate.Blobs = blobs
+		candidate.BlobHashes = hashes
 	}
-	// L2가 멈춘 경우 기존 코드 그대로 L1 가스비 이용
 
-	/*
+	txMgr.Send(txdata, candidate, receiptsCh)
+}
+
+// sendCancelTx emits a zero-data self-send tagged isCancel. Bumping the fee on a tx
+// occupying the batcher's next pending nonce is the standard way to clear a local
+// mempool slot that txpool.ErrAlreadyReserved indicates is blocked.
+func (l *BatchSubmitter) sendCancelTx(txMgr *txManager, receiptsCh chan txmgr.TxReceipt[txData]) {
+	from := l.Txmgr.From()
 	candidate := txmgr.TxCandidate{
-		To:       &l.RollupConfig.BatchInboxAddress,
-		TxData:   data,
-		GasLimit: intrinsicGas,
+		To:       &from,
+		TxData:   nil,
+		GasLimit: params.TxGas,
 	}
-	*/
-	// 위 코드를 if문 안에 집어 넣음
-
-	queue.Send(txdata, candidate, receiptsCh)
-	// Send에서 사용하는 RPC url을 L1 / L2에 따라서 할당해줘야 할텐데 이걸 어디서 설정해주는지 못찾겠음
+	txMgr.Send(txData{isCancel: true, isBlob: l.txpoolBlockedBlob}, candidate, receiptsCh)
 }
 
-func (l *BatchSubmitter) handleReceipt(r txmgr.TxReceipt[txData]) {
-	// Record TX Status
+// handleReceipt records a tx's outcome and reports whether it was a fatal failure -
+// one the driver should react to by restarting the pipeline - as opposed to a
+// recoverable txpool-blocked condition, which is handled entirely by the txpool
+// state machine above.
+func (l *BatchSubmitter) handleReceipt(txMgr *txManager, r txmgr.TxReceipt[txData]) (fatal bool) {
+	defer txMgr.Forget(r.ID.ID())
+
+	if txMgr.IsDiscardedReceipt(r.ID.ID()) {
+		l.Log.Debug("dropping receipt for a tx invalidated by an earlier pipeline restart", "id", r.ID.ID())
+		return false
+	}
+
 	if r.Err != nil {
 		l.recordFailedTx(r.ID, r.Err)
-	} else {
-		l.recordConfirmedTx(r.ID, r.Receipt)
+		if errors.Is(r.Err, txpool.ErrAlreadyReserved) {
+			l.Log.Warn("txpool rejected tx as already reserved, blocking further submissions of this type", "is_blob", r.ID.isBlob)
+			l.txpoolBlockedBlob = r.ID.isBlob
+			l.txpoolState.Store(int32(TxpoolBlocked))
+			return false
+		}
+		return true
 	}
+
+	l.recordConfirmedTx(r.ID, r.Receipt)
+	txMgr.MarkConfirmed()
+	if r.ID.isCancel {
+		l.Log.Info("txpool-blocking tx cleared by self-cancellation, resuming normal submission")
+		l.txpoolState.Store(int32(TxpoolGood))
+	}
+	return false
 }
 
 func (l *BatchSubmitter) recordL1Tip(l1tip eth.L1BlockRef) {
-	if l.lastL1Tip == l1tip {
+	if l.state.LastL1Tip() == l1tip {
 		return
 	}
-	l.lastL1Tip = l1tip
+	l.state.RecordL1Tip(l1tip)
 	l.Metr.RecordLatestL1Block(l1tip)
 }
 
@@ -514,15 +864,18 @@ func (l *BatchSubmitter) l1Tip(ctx context.Context) (eth.L1BlockRef, error) {
 	return eth.InfoToL1BlockRef(eth.HeaderBlockInfo(head)), nil
 }
 
-// 위의 l1Tip과 l2에서 같은 기능을 수행하는 l2Tip 함수
+// l2Tip gets the current tip of the L2 inbox target chain as an L2BlockRef,
+// used to pick the channel's reference tip when posting there. Unlike l1Tip,
+// this goes through L2Client.BlockByNumber rather than HeaderByNumber, since
+// deriving an L2BlockRef needs the block's L1 origin info out of the body.
 func (l *BatchSubmitter) l2Tip(ctx context.Context) (eth.L2BlockRef, error) {
 	tctx, cancel := context.WithTimeout(ctx, l.Config.NetworkTimeout)
 	defer cancel()
-	head, err := l.L1Client.HeaderByNumber(tctx, nil)
+	head, err := l.L2Client.BlockByNumber(tctx, nil)
 	if err != nil {
-		return eth.L1BlockRef{}, fmt.Errorf("getting latest L2 block: %w", err)
+		return eth.L2BlockRef{}, fmt.Errorf("getting latest L2 block: %w", err)
 	}
-	return eth.InfoToL1BlockRef(eth.HeaderBlockInfo(head)), nil
+	return derive.L2BlockToBlockRef(head, &l.RollupConfig.Genesis)
 }
 
 func logFields(xs ...any) (fs []any) {
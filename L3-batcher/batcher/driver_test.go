@@ -0,0 +1,67 @@
+package batcher
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-node/rollup"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+)
+
+type fakeL3Client struct {
+	number uint64
+}
+
+func (f fakeL3Client) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return testBlock(f.number, common.Hash{}), nil
+}
+
+func newTestSubmitter(l3Number uint64) *BatchSubmitter {
+	return &BatchSubmitter{
+		DriverSetup: DriverSetup{
+			Log:          log.Root(),
+			RollupConfig: &rollup.Config{SeqWindowSize: 10},
+			Config:       BatcherConfig{NetworkTimeout: time.Second},
+			L3Client:     fakeL3Client{number: l3Number},
+		},
+	}
+}
+
+// TestGetL2StatusHysteresis checks that getL2Status requires l2HealthHysteresis
+// consecutive disagreeing polls before it flips l2Health in either direction, so a
+// single transient poll can't flap the batch-inbox target chain.
+func TestGetL2StatusHysteresis(t *testing.T) {
+	ctx := context.Background()
+	status := &eth.SyncStatus{SafeInL2: eth.L2BlockRef{Number: 100}}
+
+	// unsafeL3 (105) is within SeqWindowSize (10) of SafeInL2 (100): healthy.
+	l := newTestSubmitter(105)
+	if got := l.getL2Status(ctx, status); got != l2Healthy {
+		t.Fatalf("got %v, want l2Healthy", got)
+	}
+
+	// unsafeL3 (120) is now more than SeqWindowSize ahead of SafeInL2: stalled,
+	// but only once l2HealthHysteresis consecutive polls have agreed.
+	l.L3Client = fakeL3Client{number: 120}
+	if got := l.getL2Status(ctx, status); got != l2Healthy {
+		t.Fatalf("flipped to stalled after a single stalled poll, got %v", got)
+	}
+	if got := l.getL2Status(ctx, status); got != l2Stalled {
+		t.Fatalf("did not flip to stalled after %d consecutive stalled polls, got %v", l2HealthHysteresis, got)
+	}
+
+	// Recovering back to healthy requires the same number of consecutive polls.
+	l.L3Client = fakeL3Client{number: 105}
+	if got := l.getL2Status(ctx, status); got != l2Stalled {
+		t.Fatalf("flipped back to healthy after a single healthy poll, got %v", got)
+	}
+	if got := l.getL2Status(ctx, status); got != l2Healthy {
+		t.Fatalf("did not recover to healthy after %d consecutive healthy polls, got %v", l2HealthHysteresis, got)
+	}
+}
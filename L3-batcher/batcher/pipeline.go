@@ -0,0 +1,100 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// pipeline owns loading L2 blocks and packing them into channel frames. It is
+// started and stopped by the top-level driver independently of the txManager, so
+// a reorg or a fatal tx failure can restart block loading from scratch without
+// tearing down in-flight tx tracking.
+type pipeline struct {
+	l *BatchSubmitter
+
+	mu      sync.Mutex
+	running bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	// reorgCh and loadedCh are how the pipeline's own goroutine reports events back
+	// to the driver's select loop, rather than invoking a callback inline - a pulse
+	// sent from within the pipeline goroutine must never be handled by code that
+	// turns around and blocks on that same goroutine exiting (e.g. Stop).
+	reorgCh  chan struct{}
+	loadedCh chan struct{}
+}
+
+func newPipeline(l *BatchSubmitter) *pipeline {
+	return &pipeline{
+		l:        l,
+		reorgCh:  make(chan struct{}, 1),
+		loadedCh: make(chan struct{}, 1),
+	}
+}
+
+// Start begins polling for new L2 blocks on l.Config.PollInterval until Stop is
+// called or ctx is done. On detecting a reorg, it pulses reorgCh and exits; on every
+// successful load it pulses loadedCh. Both pulses are non-blocking: a driver that is
+// busy handling the previous one just sees a single coalesced pulse.
+func (p *pipeline) Start(ctx context.Context) {
+	p.mu.Lock()
+	if p.running {
+		p.mu.Unlock()
+		return
+	}
+	pctx, cancel := context.WithCancel(ctx)
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	p.running = true
+	p.mu.Unlock()
+
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.l.Config.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if p.l.state.IsFull() {
+					p.l.Log.Debug("channel manager at capacity, throttling block loading", "pending_blocks", p.l.state.PendingBlocks())
+					continue
+				}
+				err := p.l.loadBlocksIntoState(pctx)
+				if errors.Is(err, ErrReorg) {
+					pulse(p.reorgCh)
+					return
+				} else if err == nil {
+					pulse(p.loadedCh)
+				}
+			case <-pctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func pulse(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Stop halts block loading and waits for the pipeline goroutine to exit. It does
+// not touch the txManager: any tx already handed off for submission keeps running.
+func (p *pipeline) Stop() {
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return
+	}
+	cancel, done := p.cancel, p.done
+	p.running = false
+	p.mu.Unlock()
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,113 @@
+package batcher
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/ethereum-optimism/optimism/op-node/rollup/derive"
+)
+
+// txID identifies a single frame produced by the channel manager.
+type txID struct {
+	chID        derive.ChannelID
+	frameNumber uint16
+}
+
+func (id txID) String() string {
+	return id.chID.String() + ":" + strconv.Itoa(int(id.frameNumber))
+}
+
+// txID and frameData keep their fields unexported so channelManager stays the
+// sole owner of frame bookkeeping, but that means encoding/json's default
+// reflection-based (un)marshaling sees no fields to encode at all - it would
+// silently round-trip every checkpointed frame as `{}`. MarshalJSON/UnmarshalJSON
+// below route through an exported mirror struct instead, so Checkpoint can still
+// persist them via plain json.Marshal/Unmarshal.
+type txIDJSON struct {
+	ChID        derive.ChannelID
+	FrameNumber uint16
+}
+
+func (id txID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(txIDJSON{ChID: id.chID, FrameNumber: id.frameNumber})
+}
+
+func (id *txID) UnmarshalJSON(data []byte) error {
+	var j txIDJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	id.chID = j.ChID
+	id.frameNumber = j.FrameNumber
+	return nil
+}
+
+// txData groups the frame(s) carried by a single L1 transaction.
+type txData struct {
+	frames []frameData
+
+	// isBlob is true if this tx's frames were (or should be) carried as EIP-4844 blobs
+	// rather than calldata.
+	isBlob bool
+	// isCancel marks a tx as a self-cancellation sent to clear a blocked txpool slot,
+	// rather than a tx carrying channel frame data.
+	isCancel bool
+}
+
+type frameData struct {
+	data []byte
+	id   txID
+}
+
+// frameDataJSON mirrors frameData's fields, exported so json.Marshal/Unmarshal
+// can see them - see the comment on txIDJSON above for why this is needed.
+type frameDataJSON struct {
+	Data []byte
+	ID   txID
+}
+
+func (f frameData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(frameDataJSON{Data: f.data, ID: f.id})
+}
+
+func (f *frameData) UnmarshalJSON(data []byte) error {
+	var j frameDataJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	f.data = j.Data
+	f.id = j.ID
+	return nil
+}
+
+func singleFrameTxData(id txID, data []byte) txData {
+	return txData{frames: []frameData{{data: data, id: id}}}
+}
+
+// ID returns the id of the first frame in this tx (channelManager.TxData builds
+// single-frame txs in both calldata and blob mode).
+// A self-cancellation tx carries no frames and reports the zero txID.
+func (td *txData) ID() txID {
+	if len(td.frames) == 0 {
+		return txID{}
+	}
+	return td.frames[0].id
+}
+
+// Bytes returns the calldata payload for this tx: the concatenation of its frame(s).
+func (td *txData) Bytes() []byte {
+	var out []byte
+	for _, f := range td.frames {
+		out = append(out, f.data...)
+	}
+	return out
+}
+
+// Len returns the length in bytes of the calldata payload for this tx.
+func (td *txData) Len() int {
+	l := 0
+	for _, f := range td.frames {
+		l += len(f.data)
+	}
+	return l
+}
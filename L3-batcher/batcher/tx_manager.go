@@ -0,0 +1,121 @@
+package batcher
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-service/txmgr"
+)
+
+// txManager owns the lifecycle of in-flight txs on behalf of the driver: nonce
+// bookkeeping, retry/replacement (via the underlying txmgr.Queue), and receipt
+// polling. Unlike the RPC's view of the pending nonce, which is unreliable under
+// load, txManager tracks lastSuccessNonce and lastPendingNonce itself as txs are
+// sent and confirmed. It is constructed once per BatchSubmitter and outlives any
+// number of pipeline restarts, so a reorg or tx failure never has to re-learn
+// in-flight state from scratch.
+type txManager struct {
+	log   log.Logger
+	queue *txmgr.Queue[txData]
+
+	mu               sync.Mutex
+	lastSuccessNonce int64
+	lastPendingNonce int64
+	discarded        map[int64]struct{}
+	// sentNonce maps the txID of every tx currently being tracked back to the
+	// nonce it was sent under, so a receipt arriving later (identified only by
+	// its txID) can be checked against discarded.
+	sentNonce map[txID]int64
+}
+
+// newTxManager constructs a txManager around a fresh txmgr.Queue.
+func newTxManager(l log.Logger, killCtx context.Context, mgr txmgr.TxManager, maxPending uint64) *txManager {
+	return &txManager{
+		log:              l,
+		queue:            txmgr.NewQueue[txData](killCtx, mgr, maxPending),
+		lastSuccessNonce: -1,
+		lastPendingNonce: -1,
+		discarded:        make(map[int64]struct{}),
+		sentNonce:        make(map[txID]int64),
+	}
+}
+
+// Send submits candidate for id, advancing lastPendingNonce.
+func (m *txManager) Send(id txData, candidate txmgr.TxCandidate, receiptsCh chan txmgr.TxReceipt[txData]) {
+	m.mu.Lock()
+	m.lastPendingNonce++
+	m.sentNonce[id.ID()] = m.lastPendingNonce
+	m.mu.Unlock()
+	m.queue.Send(id, candidate, receiptsCh)
+}
+
+// Discard marks nonce as no longer being tracked: used when a pipeline restart
+// invalidates a tx that was sent but has not yet received a receipt, so a late
+// receipt for it does not confuse lastSuccessNonce bookkeeping.
+func (m *txManager) Discard(nonce int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.discarded[nonce] = struct{}{}
+}
+
+// DiscardPending discards every nonce currently considered in flight (sent but not
+// yet confirmed or failed). A pipeline restart invalidates all of them at once,
+// since the channels/frames they carried are gone once the channel manager clears.
+func (m *txManager) DiscardPending() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for n := m.lastSuccessNonce + 1; n <= m.lastPendingNonce; n++ {
+		m.discarded[n] = struct{}{}
+	}
+}
+
+// IsDiscarded reports whether nonce was previously discarded.
+func (m *txManager) IsDiscarded(nonce int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.discarded[nonce]
+	return ok
+}
+
+// IsDiscardedReceipt reports whether the receipt for id was sent under a nonce
+// that has since been discarded (by Discard or DiscardPending), meaning the
+// receipt belongs to a tx a pipeline restart has already invalidated and
+// should not be processed.
+func (m *txManager) IsDiscardedReceipt(id txID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nonce, ok := m.sentNonce[id]
+	if !ok {
+		return false
+	}
+	_, discarded := m.discarded[nonce]
+	return discarded
+}
+
+// Forget drops the sentNonce/discarded bookkeeping held for id, once its receipt
+// has been fully handled - confirmed, failed, or dropped as discarded - so these
+// maps don't grow without bound over the life of the process.
+func (m *txManager) Forget(id txID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nonce, ok := m.sentNonce[id]
+	if !ok {
+		return
+	}
+	delete(m.sentNonce, id)
+	delete(m.discarded, nonce)
+}
+
+// MarkConfirmed advances lastSuccessNonce after a tx lands.
+func (m *txManager) MarkConfirmed() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastSuccessNonce++
+}
+
+// Wait blocks until every tx currently tracked by the underlying queue has completed.
+func (m *txManager) Wait() {
+	m.queue.Wait()
+}
@@ -0,0 +1,87 @@
+package batcher
+
+import "testing"
+
+// newTestTxManager builds a txManager with its nonce-bookkeeping fields ready to
+// use, without going through newTxManager - which would require a real
+// txmgr.TxManager/queue that these tests have no need to exercise.
+func newTestTxManager() *txManager {
+	return &txManager{
+		lastSuccessNonce: -1,
+		lastPendingNonce: -1,
+		discarded:        make(map[int64]struct{}),
+		sentNonce:        make(map[txID]int64),
+	}
+}
+
+// TestTxManagerDiscardPending checks that DiscardPending marks every nonce
+// after lastSuccessNonce, up to and including lastPendingNonce, as discarded, and
+// leaves already-confirmed nonces alone.
+func TestTxManagerDiscardPending(t *testing.T) {
+	m := newTestTxManager()
+	m.lastSuccessNonce = 1
+	m.lastPendingNonce = 4
+
+	m.DiscardPending()
+
+	if m.IsDiscarded(1) {
+		t.Fatal("IsDiscarded(1) = true, want false: nonce 1 was already confirmed")
+	}
+	for _, n := range []int64{2, 3, 4} {
+		if !m.IsDiscarded(n) {
+			t.Fatalf("IsDiscarded(%d) = false, want true: nonce was in flight", n)
+		}
+	}
+	if m.IsDiscarded(5) {
+		t.Fatal("IsDiscarded(5) = true, want false: nonce was never sent")
+	}
+}
+
+// TestTxManagerIsDiscardedReceipt checks that a receipt is reported discarded
+// only if the nonce it was sent under has been discarded, looked up via sentNonce.
+func TestTxManagerIsDiscardedReceipt(t *testing.T) {
+	m := newTestTxManager()
+	discardedID := txID{frameNumber: 1}
+	liveID := txID{frameNumber: 2}
+	unknownID := txID{frameNumber: 3}
+
+	m.sentNonce[discardedID] = 5
+	m.sentNonce[liveID] = 6
+	m.Discard(5)
+
+	if !m.IsDiscardedReceipt(discardedID) {
+		t.Fatal("IsDiscardedReceipt(discardedID) = false, want true")
+	}
+	if m.IsDiscardedReceipt(liveID) {
+		t.Fatal("IsDiscardedReceipt(liveID) = true, want false: its nonce was never discarded")
+	}
+	if m.IsDiscardedReceipt(unknownID) {
+		t.Fatal("IsDiscardedReceipt(unknownID) = true, want false: id was never sent")
+	}
+}
+
+// TestTxManagerForget checks that Forget drops both the sentNonce entry for id
+// and the corresponding discarded entry, so a later receipt for the same id is no
+// longer reported discarded and the maps don't grow without bound.
+func TestTxManagerForget(t *testing.T) {
+	m := newTestTxManager()
+	id := txID{frameNumber: 7}
+	m.sentNonce[id] = 9
+	m.Discard(9)
+
+	if !m.IsDiscardedReceipt(id) {
+		t.Fatal("IsDiscardedReceipt(id) = false before Forget, want true")
+	}
+
+	m.Forget(id)
+
+	if _, ok := m.sentNonce[id]; ok {
+		t.Fatal("Forget did not remove the sentNonce entry")
+	}
+	if _, ok := m.discarded[9]; ok {
+		t.Fatal("Forget did not remove the discarded entry")
+	}
+	if m.IsDiscardedReceipt(id) {
+		t.Fatal("IsDiscardedReceipt(id) = true after Forget, want false: id is no longer tracked")
+	}
+}
@@ -0,0 +1,34 @@
+package batcher
+
+import "testing"
+
+// TestTxpoolBlocksDAType checks that a blocked txpool slot only holds back
+// frames of the DA type that actually got rejected, leaving the other type
+// free to keep flowing, and that TxpoolGood/TxpoolCancelPending never hold
+// anything back via this check (TxpoolCancelPending is handled separately, by
+// publishTxToL1 returning before resolving a DA type at all).
+func TestTxpoolBlocksDAType(t *testing.T) {
+	cases := []struct {
+		name        string
+		state       txpoolState
+		blockedBlob bool
+		daType      DataAvailabilityType
+		want        bool
+	}{
+		{"good, calldata", TxpoolGood, false, CalldataType, false},
+		{"good, blobs", TxpoolGood, true, BlobsType, false},
+		{"blocked calldata, next is calldata", TxpoolBlocked, false, CalldataType, true},
+		{"blocked calldata, next is blobs", TxpoolBlocked, false, BlobsType, false},
+		{"blocked blobs, next is blobs", TxpoolBlocked, true, BlobsType, true},
+		{"blocked blobs, next is calldata", TxpoolBlocked, true, CalldataType, false},
+		{"cancel pending, calldata", TxpoolCancelPending, false, CalldataType, false},
+		{"cancel pending, blobs", TxpoolCancelPending, true, BlobsType, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := txpoolBlocksDAType(c.state, c.blockedBlob, c.daType); got != c.want {
+				t.Fatalf("txpoolBlocksDAType(%v, %v, %v) = %v, want %v", c.state, c.blockedBlob, c.daType, got, c.want)
+			}
+		})
+	}
+}